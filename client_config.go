@@ -0,0 +1,133 @@
+package hdfs
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// defaultBlockSize and defaultReplication mirror Hadoop's own built-in
+// defaults (dfs.blocksize and dfs.replication), used when a config doesn't
+// set them explicitly.
+const (
+	defaultBlockSize   = 128 << 20
+	defaultReplication = 3
+)
+
+// HadoopClientConfig bundles everything a client needs to dial HDFS: the
+// resolved user, the namenode addresses to try (HA-aware), and a handful of
+// client-relevant defaults. It's the one-stop entry point for building an
+// HDFS client, instead of every caller reaching into env vars and HadoopConf
+// itself.
+type HadoopClientConfig struct {
+	// User is the effective user for RPCs, from HADOOP_USER_NAME or
+	// falling back to the OS user.
+	User string
+	// Namenodes are the addresses of conf's default filesystem, in the
+	// order they should be tried for failover.
+	Namenodes []string
+	// Nameservices holds every nameservice listed in dfs.nameservices, or
+	// nil if that key isn't set. Namenodes above only covers the default one.
+	Nameservices []Nameservice
+	// DefaultBlockSize is dfs.blocksize, in bytes.
+	DefaultBlockSize int64
+	// Replication is dfs.replication.
+	Replication int
+	// SocksServer is hadoop.socks.server ("host:port"), or empty if unset.
+	SocksServer string
+}
+
+// LoadClientConfig loads the hadoop config from paths (see LoadHadoopConfE)
+// and builds a HadoopClientConfig from it.
+func LoadClientConfig(paths ...string) (*HadoopClientConfig, error) {
+	conf, err := LoadHadoopConfE(paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	return conf.ClientConfig()
+}
+
+// ClientConfig builds a HadoopClientConfig from conf, resolving the calling
+// user and the default filesystem's namenodes.
+func (conf HadoopConf) ClientConfig() (*HadoopClientConfig, error) {
+	username, err := resolveUser()
+	if err != nil {
+		return nil, err
+	}
+
+	namenodes, err := conf.Namenodes("")
+	if err != nil {
+		return nil, err
+	}
+
+	nameservices, err := conf.Nameservices()
+	if err != nil {
+		return nil, err
+	}
+
+	return &HadoopClientConfig{
+		User:             username,
+		Namenodes:        namenodes,
+		Nameservices:     nameservices,
+		DefaultBlockSize: parseSize(conf["dfs.blocksize"], defaultBlockSize),
+		Replication:      parseIntOrDefault(conf["dfs.replication"], defaultReplication),
+		SocksServer:      conf["hadoop.socks.server"],
+	}, nil
+}
+
+// resolveUser returns HADOOP_USER_NAME if set, or else the current OS user,
+// matching Hadoop's own client-side user resolution.
+func resolveUser() (string, error) {
+	if name := os.Getenv("HADOOP_USER_NAME"); name != "" {
+		return name, nil
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("resolving effective user: %s", err)
+	}
+
+	return u.Username, nil
+}
+
+var sizeSuffixes = map[string]int64{
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+// parseSize parses a byte size that may carry a k/m/g/t suffix (as
+// dfs.blocksize does in recent Hadoop versions), falling back to def if s is
+// empty or malformed.
+func parseSize(s string, def int64) int64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def
+	}
+
+	mult := int64(1)
+	if last := strings.ToLower(s[len(s)-1:]); sizeSuffixes[last] != 0 {
+		mult = sizeSuffixes[last]
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return def
+	}
+
+	return n * mult
+}
+
+func parseIntOrDefault(s string, def int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return def
+	}
+
+	return n
+}
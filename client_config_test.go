@@ -0,0 +1,62 @@
+package hdfs
+
+import (
+	"os"
+	"os/user"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		in   string
+		def  int64
+		want int64
+	}{
+		{"134217728", 1, 134217728},
+		{"128k", 1, 128 << 10},
+		{"128K", 1, 128 << 10},
+		{"128m", 1, 128 << 20},
+		{"1g", 1, 1 << 30},
+		{"1t", 1, 1 << 40},
+		{"  64m  ", 1, 64 << 20},
+		{"", 42, 42},
+		{"not-a-size", 42, 42},
+		{"128x", 42, 42},
+	}
+
+	for _, c := range cases {
+		if got := parseSize(c.in, c.def); got != c.want {
+			t.Errorf("parseSize(%q, %d) = %d, want %d", c.in, c.def, got, c.want)
+		}
+	}
+}
+
+func TestResolveUserFromEnv(t *testing.T) {
+	os.Setenv("HADOOP_USER_NAME", "alice")
+	defer os.Unsetenv("HADOOP_USER_NAME")
+
+	got, err := resolveUser()
+	if err != nil {
+		t.Fatalf("resolveUser: %s", err)
+	}
+	if want := "alice"; got != want {
+		t.Errorf("resolveUser = %q, want %q", got, want)
+	}
+}
+
+func TestResolveUserFallsBackToOSUser(t *testing.T) {
+	os.Unsetenv("HADOOP_USER_NAME")
+
+	want, err := user.Current()
+	if err != nil {
+		t.Skipf("os/user.Current unavailable in this environment: %s", err)
+	}
+
+	got, err := resolveUser()
+	if err != nil {
+		t.Fatalf("resolveUser: %s", err)
+	}
+	if got != want.Username {
+		t.Errorf("resolveUser = %q, want %q", got, want.Username)
+	}
+}
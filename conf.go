@@ -1,112 +1,217 @@
 package hdfs
 
 import (
-	"encoding/xml"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"log"
 	"sort"
 	"strings"
 )
 
-// Property is the struct representation of hadoop configuration
-// key value pair.
-type Property struct {
-	Name  string `xml:"name"`
-	Value string `xml:"value"`
-}
-
-type propertyList struct {
-	Property []Property `xml:"property"`
-}
-
 // HadoopConf represents a map of all the key value configutation
 // pairs found in a user's hadoop configuration files.
 type HadoopConf map[string]string
 
-var defaultFS string
+// NamenodeAddr identifies a single namenode within a nameservice. ID is the
+// logical namenode id (e.g. "nn1") as configured under dfs.ha.namenodes.<ns>;
+// it is empty for a non-HA nameservice that has no logical id of its own.
+type NamenodeAddr struct {
+	ID      string
+	Address string
+}
+
+// Nameservice represents one entry of dfs.nameservices, resolved to the
+// concrete namenode addresses that back it. For a non-HA nameservice this
+// contains a single NamenodeAddr.
+type Nameservice struct {
+	ID        string
+	Namenodes []NamenodeAddr
+}
 
 var errUnresolvedDefaultFS = errors.New("no defaultFS in configuration")
 var errUnresolvedNamenode = errors.New("no namenode address in configuration")
 
-// LoadHadoopConf returns a HadoopConf object representing configuration from
-// the specified path, or finds the correct path in the environment. If
-// path or the env variable HADOOP_CONF_DIR is specified, it should point
-// directly to the directory where the xml files are. If neither is specified,
-// ${HADOOP_HOME}/conf will be used.
-func LoadHadoopConf(path string) HadoopConf {
-
-	if path == "" {
-		path = os.Getenv("HADOOP_CONF_DIR")
-		if path == "" {
-			path = filepath.Join(os.Getenv("HADOOP_HOME"), "conf")
+// DefaultFS returns conf's fs.defaultFS, with any hdfs:// or viewfs://
+// scheme and trailing slash stripped. For a viewfs:// FS this is the mount
+// table name, not an underlying nameservice; see Namenodes for how that gets
+// resolved further.
+func (conf HadoopConf) DefaultFS() (string, error) {
+	fsName := conf["fs.defaultFS"]
+	if fsName == "" {
+		return "", errUnresolvedDefaultFS
+	}
+
+	fsName = strings.TrimPrefix(fsName, "hdfs://")
+	fsName = strings.TrimPrefix(fsName, "viewfs://")
+	fsName = strings.TrimSuffix(fsName, "/")
+	return fsName, nil
+}
+
+// Namenodes returns the namenode hosts present in the configuration for
+// givenFS (or, if givenFS is empty, for fs.defaultFS, see DefaultFS). It
+// understands HA nameservices (dfs.ha.namenodes.<ns> plus
+// dfs.namenode.rpc-address.<ns>.<nnid>) and plain non-HA namenodes. For a
+// viewfs:// FS it follows the mount table's root link
+// (fs.viewfs.mounttable.<name>.link./) to the underlying cluster and resolves
+// that instead; per-path mount points aren't consulted, so this only covers a
+// mount table that maps its whole namespace onto one cluster, not one that
+// routes different paths to different clusters. Federated clusters with more
+// than one nameservice should use Nameservices instead, which resolves all of
+// them at once. The returned slice will be sorted and deduped.
+//
+// Namenodes is pure with respect to conf: it never reads or writes package
+// state, so it's safe to call concurrently with different HadoopConf values.
+func (conf HadoopConf) Namenodes(givenFS string) ([]string, error) {
+	rawFS := givenFS
+	if rawFS == "" {
+		rawFS = conf["fs.defaultFS"]
+		if rawFS == "" {
+			return nil, errUnresolvedDefaultFS
 		}
 	}
 
-	hadoopConf := make(HadoopConf)
-	for _, file := range []string{"core-site.xml", "hdfs-site.xml"} {
-		pList := propertyList{}
-		f, err := ioutil.ReadFile(filepath.Join(path, file))
+	isViewFS := strings.HasPrefix(rawFS, "viewfs://")
+	fsName := strings.TrimPrefix(rawFS, "hdfs://")
+	fsName = strings.TrimPrefix(fsName, "viewfs://")
+	fsName = strings.TrimSuffix(fsName, "/")
+
+	if isViewFS {
+		target, err := conf.viewfsRootTarget(fsName)
 		if err != nil {
-			continue
+			return nil, err
 		}
+		fsName = target
+	}
+
+	// fsName is usually a nameservice id (HA or not); resolve it that way
+	// first, then fall back to treating it as a literal host:port.
+	addrs, err := conf.namenodeAddrsForNS(fsName)
+	if err == nil {
+		return addrsToHosts(addrs), nil
+	}
+	if strings.Contains(fsName, ":") {
+		return []string{fsName}, nil
+	}
+
+	return nil, errUnresolvedNamenode
+}
+
+// viewfsRootTarget resolves the root mount link of a viewfs mount table
+// (fs.viewfs.mounttable.<name>.link./) to the nameservice id or host:port it
+// points at. Only the root link is consulted, so a mount table that routes
+// individual subpaths to different clusters via additional link.<path>
+// entries is not supported.
+func (conf HadoopConf) viewfsRootTarget(name string) (string, error) {
+	key := fmt.Sprintf("fs.viewfs.mounttable.%s.link./", name)
+	target := conf[key]
+	if target == "" {
+		return "", fmt.Errorf("no root mount link (%s) for viewfs mount table %q", key, name)
+	}
 
-		err = xml.Unmarshal(f, &pList)
+	target = strings.TrimPrefix(target, "hdfs://")
+	target = strings.TrimSuffix(target, "/")
+	if i := strings.Index(target, "/"); i >= 0 {
+		target = target[:i]
+	}
+
+	return target, nil
+}
+
+// Nameservices returns every nameservice listed in dfs.nameservices, each
+// resolved to its concrete namenode addresses. It returns a nil slice (and no
+// error) when dfs.nameservices isn't set, which is the common non-federated
+// case; callers should fall back to Namenodes in that case.
+func (conf HadoopConf) Nameservices() ([]Nameservice, error) {
+	nsList := conf["dfs.nameservices"]
+	if nsList == "" {
+		return nil, nil
+	}
+
+	nameservices := make([]Nameservice, 0, len(splitAndTrim(nsList)))
+	for _, ns := range splitAndTrim(nsList) {
+		addrs, err := conf.namenodeAddrsForNS(ns)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("nameservice %s: %s", ns, err)
 		}
+		nameservices = append(nameservices, Nameservice{ID: ns, Namenodes: addrs})
+	}
 
-		for _, prop := range pList.Property {
-			hadoopConf[prop.Name] = prop.Value
-		}
+	return nameservices, nil
+}
+
+// NamenodesForNS returns the sorted, deduped namenode addresses for a single
+// nameservice id, as found via dfs.ha.namenodes.<ns> (or, for a non-HA
+// nameservice, dfs.namenode.rpc-address.<ns> directly).
+func (conf HadoopConf) NamenodesForNS(ns string) ([]string, error) {
+	addrs, err := conf.namenodeAddrsForNS(ns)
+	if err != nil {
+		return nil, err
 	}
 
-	return hadoopConf
+	return addrsToHosts(addrs), nil
 }
 
-// Namenodes returns the namenode hosts present in the configuration. The
-// returned slice will be sorted and deduped.
-func (conf HadoopConf) Namenodes(givenFS string) ([]string, error) {
-	nns := make(map[string]bool)
-	var defaultFsName string
-	if givenFS == "" {
-		// find fs name first
-		for key, value := range conf {
-			if key == "fs.defaultFS" {
-				defaultFsName = strings.TrimPrefix(value, "hdfs://")
-			}
+// namenodeAddrsForNS resolves a single nameservice id to its namenode
+// addresses, trying the HA layout first and falling back to the single,
+// non-HA rpc-address key.
+func (conf HadoopConf) namenodeAddrsForNS(ns string) ([]NamenodeAddr, error) {
+	nnids := conf[fmt.Sprintf("dfs.ha.namenodes.%s", ns)]
+	if nnids == "" {
+		addr := conf[fmt.Sprintf("dfs.namenode.rpc-address.%s", ns)]
+		if addr == "" {
+			return nil, errUnresolvedNamenode
 		}
-		if defaultFsName == "" {
-			return nil, errUnresolvedDefaultFS
-		}
-		defaultFS = defaultFsName
-	} else {
-		defaultFS = givenFS
+		return []NamenodeAddr{{Address: addr}}, nil
 	}
 
-	// extract default FS cluster
-	for key, value := range conf {
-		k := fmt.Sprintf("dfs.namenode.rpc-address.%s.", defaultFsName)
-		if strings.HasPrefix(key, k) {
-			nns[value] = true
+	var addrs []NamenodeAddr
+	for _, nnid := range splitAndTrim(nnids) {
+		addr := conf[fmt.Sprintf("dfs.namenode.rpc-address.%s.%s", ns, nnid)]
+		if addr == "" {
+			continue
 		}
+		addrs = append(addrs, NamenodeAddr{ID: nnid, Address: addr})
 	}
 
-	if len(nns) == 0 {
+	if len(addrs) == 0 {
 		return nil, errUnresolvedNamenode
 	}
 
-	keys := make([]string, 0, len(nns))
-	for k, _ := range nns {
-		keys = append(keys, k)
+	return addrs, nil
+}
+
+func addrsToHosts(addrs []NamenodeAddr) []string {
+	seen := make(map[string]bool, len(addrs))
+	hosts := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if seen[addr.Address] {
+			continue
+		}
+		seen[addr.Address] = true
+		hosts = append(hosts, addr.Address)
 	}
 
-	sort.Strings(keys)
-	return keys, nil
+	sort.Strings(hosts)
+	return hosts
+}
+
+func splitAndTrim(s string) []string {
+	parts := strings.Split(s, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
 }
 
+// GetDefaultFS is deprecated and always returns an empty string: it used to
+// read a package-level global that Namenodes mutated as a side effect, which
+// made HadoopConf unsafe to use concurrently across different clusters. Use
+// HadoopConf.DefaultFS() instead.
 func GetDefaultFS() string {
-	return defaultFS
+	log.Print("hdfs: GetDefaultFS is deprecated and no longer functional; use HadoopConf.DefaultFS instead")
+	return ""
 }
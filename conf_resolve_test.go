@@ -0,0 +1,108 @@
+package hdfs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamenodesHA(t *testing.T) {
+	conf := HadoopConf{
+		"fs.defaultFS":                           "hdfs://mycluster",
+		"dfs.nameservices":                       "mycluster",
+		"dfs.ha.namenodes.mycluster":             "nn1,nn2",
+		"dfs.namenode.rpc-address.mycluster.nn1": "nn1.example.com:8020",
+		"dfs.namenode.rpc-address.mycluster.nn2": "nn2.example.com:8020",
+	}
+
+	got, err := conf.Namenodes("")
+	if err != nil {
+		t.Fatalf("Namenodes: %s", err)
+	}
+
+	want := []string{"nn1.example.com:8020", "nn2.example.com:8020"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Namenodes = %v, want %v", got, want)
+	}
+}
+
+func TestNameservicesFederation(t *testing.T) {
+	conf := HadoopConf{
+		"dfs.nameservices":                 "ns1,ns2",
+		"dfs.ha.namenodes.ns1":             "nn1,nn2",
+		"dfs.namenode.rpc-address.ns1.nn1": "ns1-nn1:8020",
+		"dfs.namenode.rpc-address.ns1.nn2": "ns1-nn2:8020",
+		"dfs.namenode.rpc-address.ns2":     "ns2-nn:8020",
+	}
+
+	got, err := conf.Nameservices()
+	if err != nil {
+		t.Fatalf("Nameservices: %s", err)
+	}
+
+	want := []Nameservice{
+		{
+			ID: "ns1",
+			Namenodes: []NamenodeAddr{
+				{ID: "nn1", Address: "ns1-nn1:8020"},
+				{ID: "nn2", Address: "ns1-nn2:8020"},
+			},
+		},
+		{
+			ID:        "ns2",
+			Namenodes: []NamenodeAddr{{Address: "ns2-nn:8020"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Nameservices = %+v, want %+v", got, want)
+	}
+}
+
+func TestNamenodesViewFSRootMount(t *testing.T) {
+	conf := HadoopConf{
+		"fs.defaultFS":                             "viewfs://mycluster",
+		"fs.viewfs.mounttable.mycluster.link./":    "hdfs://realcluster",
+		"dfs.ha.namenodes.realcluster":             "nn1",
+		"dfs.namenode.rpc-address.realcluster.nn1": "nn1.example.com:8020",
+	}
+
+	got, err := conf.Namenodes("")
+	if err != nil {
+		t.Fatalf("Namenodes: %s", err)
+	}
+
+	want := []string{"nn1.example.com:8020"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Namenodes = %v, want %v", got, want)
+	}
+}
+
+func TestNamenodesViewFSNoMountTable(t *testing.T) {
+	// A viewfs name with no fs.viewfs.mounttable.<name>.link./ root link
+	// can't be resolved to an underlying cluster and must error, rather
+	// than silently treating the mount table name as a nameservice id.
+	conf := HadoopConf{
+		"fs.defaultFS":                           "viewfs://mycluster",
+		"dfs.ha.namenodes.mycluster":             "nn1",
+		"dfs.namenode.rpc-address.mycluster.nn1": "nn1.example.com:8020",
+	}
+
+	if _, err := conf.Namenodes(""); err == nil {
+		t.Fatal("Namenodes: expected an error for a viewfs name with no mount table, got nil")
+	}
+}
+
+func TestNamenodesLiteralAddress(t *testing.T) {
+	conf := HadoopConf{
+		"fs.defaultFS": "hdfs://nn.example.com:8020",
+	}
+
+	got, err := conf.Namenodes("")
+	if err != nil {
+		t.Fatalf("Namenodes: %s", err)
+	}
+
+	want := []string{"nn.example.com:8020"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Namenodes = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package hdfs
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestNamenodesConcurrent exercises Namenodes on distinct HadoopConf values
+// from many goroutines at once, guarding against regressions back to the
+// package-level defaultFS global Namenodes used to mutate.
+func TestNamenodesConcurrent(t *testing.T) {
+	confs := []HadoopConf{
+		{
+			"fs.defaultFS":                       "hdfs://cluster-a",
+			"dfs.namenode.rpc-address.cluster-a": "nn-a:8020",
+		},
+		{
+			"fs.defaultFS":                       "hdfs://cluster-b",
+			"dfs.namenode.rpc-address.cluster-b": "nn-b:8020",
+		},
+	}
+	want := []string{"nn-a:8020", "nn-b:8020"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for j, conf := range confs {
+			wg.Add(1)
+			go func(conf HadoopConf, want string) {
+				defer wg.Done()
+
+				got, err := conf.Namenodes("")
+				if err != nil {
+					t.Errorf("Namenodes: %s", err)
+					return
+				}
+				if len(got) != 1 || got[0] != want {
+					t.Errorf("Namenodes = %v, want [%s]", got, want)
+				}
+			}(conf, want[j])
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,232 @@
+package hdfs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Property is the struct representation of hadoop configuration
+// key value pair.
+type Property struct {
+	Name  string `xml:"name"`
+	Value string `xml:"value"`
+	Final string `xml:"final"`
+}
+
+type propertyList struct {
+	Property []Property `xml:"property"`
+}
+
+// confFiles lists the configuration files Hadoop itself loads, in the order
+// their properties take precedence: later files win, except for properties
+// marked <final>true</final> in an earlier one.
+var confFiles = []string{
+	"core-default.xml",
+	"core-site.xml",
+	"hdfs-default.xml",
+	"hdfs-site.xml",
+	"mapred-site.xml",
+	"yarn-site.xml",
+}
+
+var varPattern = regexp.MustCompile(`\$\{[^}]+\}`)
+
+// ConfSourcer is implemented by configuration results that can report which
+// file a given key was loaded from. It's useful for debugging a user's
+// config when several conf dirs or files are in play.
+type ConfSourcer interface {
+	// Source returns the path of the file a key was loaded from, and
+	// whether the key was found at all.
+	Source(key string) (path string, ok bool)
+}
+
+// ConfWithSource is a HadoopConf along with the file each of its keys was
+// loaded from.
+type ConfWithSource struct {
+	HadoopConf
+	sources map[string]string
+}
+
+// Source implements ConfSourcer.
+func (c *ConfWithSource) Source(key string) (string, bool) {
+	path, ok := c.sources[key]
+	return path, ok
+}
+
+// LoadHadoopConf returns a HadoopConf object representing configuration from
+// the specified path, or finds the correct path in the environment. If
+// path or the env variable HADOOP_CONF_DIR is specified, it should point
+// directly to the directory where the xml files are. A non-empty path is the
+// only directory searched. If path is empty, the HADOOP_CONF_DIR entries are
+// searched first, followed by /etc/hadoop/conf, ${HADOOP_HOME}/etc/hadoop and
+// ${HADOOP_HOME}/conf. Parse errors are swallowed for compatibility; use
+// LoadHadoopConfE to see them.
+func LoadHadoopConf(path string) HadoopConf {
+	var paths []string
+	if path != "" {
+		paths = []string{path}
+	}
+
+	conf, _ := LoadHadoopConfE(paths...)
+	return conf
+}
+
+// LoadHadoopConfE loads and merges core-default.xml, core-site.xml,
+// hdfs-default.xml, hdfs-site.xml, mapred-site.xml, and yarn-site.xml, in
+// that order, from the given directories. If no paths are given, it searches
+// the colon-separated HADOOP_CONF_DIR env var, then /etc/hadoop/conf, then
+// $HADOOP_HOME/etc/hadoop and $HADOOP_HOME/conf. For each file name, the
+// first directory that contains it wins; a directory missing a file is not
+// an error, but a file that fails to parse is. Properties marked
+// <final>true</final> cannot be overridden by a later file, and ${var}
+// references are substituted against other properties and then the
+// environment, with cycle detection.
+func LoadHadoopConfE(paths ...string) (HadoopConf, error) {
+	conf, _, err := loadHadoopConf(paths)
+	return conf, err
+}
+
+// LoadHadoopConfWithSource behaves like LoadHadoopConfE, but additionally
+// tracks which file each property was loaded from.
+func LoadHadoopConfWithSource(paths ...string) (*ConfWithSource, error) {
+	conf, sources, err := loadHadoopConf(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfWithSource{HadoopConf: conf, sources: sources}, nil
+}
+
+func loadHadoopConf(paths []string) (HadoopConf, map[string]string, error) {
+	dirs := confDirs(paths)
+
+	hadoopConf := make(HadoopConf)
+	sources := make(map[string]string)
+	finalKeys := make(map[string]bool)
+
+	for _, file := range confFiles {
+		fullPath, data, ok := readFirst(dirs, file)
+		if !ok {
+			continue
+		}
+
+		pList := propertyList{}
+		if err := xml.Unmarshal(data, &pList); err != nil {
+			return nil, nil, fmt.Errorf("parsing %s: %s", fullPath, err)
+		}
+
+		for _, prop := range pList.Property {
+			if finalKeys[prop.Name] {
+				continue
+			}
+
+			hadoopConf[prop.Name] = prop.Value
+			sources[prop.Name] = fullPath
+			if strings.EqualFold(strings.TrimSpace(prop.Final), "true") {
+				finalKeys[prop.Name] = true
+			}
+		}
+	}
+
+	if err := substituteVars(hadoopConf); err != nil {
+		return nil, nil, err
+	}
+
+	return hadoopConf, sources, nil
+}
+
+// confDirs returns the ordered list of directories to search for hadoop
+// config files, following the same precedence as LoadHadoopConfE. The
+// HADOOP_CONF_DIR/system fallback dirs only apply when the caller didn't
+// pass explicit paths; an explicit path is used as-is, exactly as the
+// original single-path LoadHadoopConf did, so a per-job or test conf dir
+// can't pick up unrelated properties from a system Hadoop install.
+func confDirs(paths []string) []string {
+	if len(paths) > 0 {
+		return append([]string{}, paths...)
+	}
+
+	var dirs []string
+	if v := os.Getenv("HADOOP_CONF_DIR"); v != "" {
+		dirs = append(dirs, filepath.SplitList(v)...)
+	}
+
+	dirs = append(dirs, "/etc/hadoop/conf")
+	if home := os.Getenv("HADOOP_HOME"); home != "" {
+		dirs = append(dirs, filepath.Join(home, "etc", "hadoop"), filepath.Join(home, "conf"))
+	}
+
+	return dirs
+}
+
+func readFirst(dirs []string, file string) (path string, data []byte, ok bool) {
+	for _, dir := range dirs {
+		p := filepath.Join(dir, file)
+		data, err := ioutil.ReadFile(p)
+		if err == nil {
+			return p, data, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// substituteVars resolves ${key} references in every property's value,
+// against other properties first and then environment variables, detecting
+// reference cycles.
+func substituteVars(conf HadoopConf) error {
+	resolved := make(map[string]bool, len(conf))
+	for key := range conf {
+		if err := resolveVars(conf, key, resolved, make(map[string]bool)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resolveVars(conf HadoopConf, key string, resolved, visiting map[string]bool) error {
+	if resolved[key] {
+		return nil
+	}
+	if visiting[key] {
+		return fmt.Errorf("hdfs: cyclic ${} reference involving %q", key)
+	}
+
+	visiting[key] = true
+	defer delete(visiting, key)
+
+	var firstErr error
+	value := varPattern.ReplaceAllStringFunc(conf[key], func(ref string) string {
+		if firstErr != nil {
+			return ref
+		}
+
+		name := ref[2 : len(ref)-1]
+		if _, ok := conf[name]; ok {
+			if err := resolveVars(conf, name, resolved, visiting); err != nil {
+				firstErr = err
+				return ref
+			}
+			return conf[name]
+		}
+
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		}
+
+		return ref
+	})
+	if firstErr != nil {
+		return firstErr
+	}
+
+	conf[key] = value
+	resolved[key] = true
+	return nil
+}
@@ -0,0 +1,153 @@
+package hdfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfFile(t *testing.T, dir, name, body string) {
+	t.Helper()
+
+	xml := "<configuration>" + body + "</configuration>"
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(xml), 0644); err != nil {
+		t.Fatalf("writing %s: %s", name, err)
+	}
+}
+
+func property(name, value string) string {
+	return "<property><name>" + name + "</name><value>" + value + "</value></property>"
+}
+
+func finalProperty(name, value string) string {
+	return "<property><name>" + name + "</name><value>" + value + "</value><final>true</final></property>"
+}
+
+func TestLoadHadoopConfPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hdfs-conf")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfFile(t, dir, "core-site.xml", property("my.key", "from-core-site"))
+	writeConfFile(t, dir, "hdfs-site.xml", property("my.key", "from-hdfs-site"))
+
+	conf, err := LoadHadoopConfE(dir)
+	if err != nil {
+		t.Fatalf("LoadHadoopConfE: %s", err)
+	}
+
+	// hdfs-site.xml is loaded after core-site.xml, so it should win.
+	if got, want := conf["my.key"], "from-hdfs-site"; got != want {
+		t.Errorf("my.key = %q, want %q", got, want)
+	}
+}
+
+func TestLoadHadoopConfFinal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hdfs-conf")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfFile(t, dir, "core-site.xml", finalProperty("my.key", "from-core-site"))
+	writeConfFile(t, dir, "hdfs-site.xml", property("my.key", "from-hdfs-site"))
+
+	conf, err := LoadHadoopConfE(dir)
+	if err != nil {
+		t.Fatalf("LoadHadoopConfE: %s", err)
+	}
+
+	// my.key is final in core-site.xml, so hdfs-site.xml can't override it.
+	if got, want := conf["my.key"], "from-core-site"; got != want {
+		t.Errorf("my.key = %q, want %q", got, want)
+	}
+}
+
+func TestLoadHadoopConfVarSubstitution(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hdfs-conf")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("HDFS_TEST_VAR_SUBSTITUTION", "env-value")
+	defer os.Unsetenv("HDFS_TEST_VAR_SUBSTITUTION")
+
+	writeConfFile(t, dir, "core-site.xml",
+		property("my.base", "base-value")+
+			property("my.from.prop", "${my.base}/suffix")+
+			property("my.from.env", "${HDFS_TEST_VAR_SUBSTITUTION}/suffix"))
+
+	conf, err := LoadHadoopConfE(dir)
+	if err != nil {
+		t.Fatalf("LoadHadoopConfE: %s", err)
+	}
+
+	if got, want := conf["my.from.prop"], "base-value/suffix"; got != want {
+		t.Errorf("my.from.prop = %q, want %q", got, want)
+	}
+	if got, want := conf["my.from.env"], "env-value/suffix"; got != want {
+		t.Errorf("my.from.env = %q, want %q", got, want)
+	}
+}
+
+func TestLoadHadoopConfExplicitPathIgnoresSystemDirs(t *testing.T) {
+	explicitDir, err := ioutil.TempDir("", "hdfs-conf-explicit")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(explicitDir)
+
+	// The explicit dir is missing hdfs-site.xml entirely, and doesn't set
+	// my.system.key at all.
+	writeConfFile(t, explicitDir, "core-site.xml", property("my.key", "from-explicit-dir"))
+
+	systemHome, err := ioutil.TempDir("", "hdfs-conf-system")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(systemHome)
+
+	systemConfDir := filepath.Join(systemHome, "etc", "hadoop")
+	if err := os.MkdirAll(systemConfDir, 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	writeConfFile(t, systemConfDir, "hdfs-site.xml",
+		property("my.key", "from-system-dir")+property("my.system.key", "leaked"))
+
+	os.Setenv("HADOOP_HOME", systemHome)
+	defer os.Unsetenv("HADOOP_HOME")
+	os.Unsetenv("HADOOP_CONF_DIR")
+
+	conf, err := LoadHadoopConfE(explicitDir)
+	if err != nil {
+		t.Fatalf("LoadHadoopConfE: %s", err)
+	}
+
+	// The explicit dir's own value must win, and nothing from the "system"
+	// HADOOP_HOME dir should have leaked in.
+	if got, want := conf["my.key"], "from-explicit-dir"; got != want {
+		t.Errorf("my.key = %q, want %q", got, want)
+	}
+	if v, ok := conf["my.system.key"]; ok {
+		t.Errorf("my.system.key leaked from system conf dir: %q", v)
+	}
+}
+
+func TestLoadHadoopConfVarCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hdfs-conf")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfFile(t, dir, "core-site.xml",
+		property("my.a", "${my.b}")+property("my.b", "${my.a}"))
+
+	if _, err := LoadHadoopConfE(dir); err == nil {
+		t.Fatal("LoadHadoopConfE: expected an error for a cyclic ${} reference, got nil")
+	}
+}